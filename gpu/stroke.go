@@ -51,7 +51,7 @@ func (qs *strokeQuads) pen() f32.Point {
 }
 
 func (qs *strokeQuads) lineTo(pt f32.Point) {
-	end := (*qs)[len(*qs)-1].quad.To
+	end := qs.pen()
 	*qs = append(*qs, strokeQuad{
 		quad: ops.Quad{
 			From: end,
@@ -61,6 +61,8 @@ func (qs *strokeQuads) lineTo(pt f32.Point) {
 	})
 }
 
+// arc appends a circular (or elliptical) arc from the current pen position,
+// in the same terms as clip.Path.Arc.
 func (qs *strokeQuads) arc(f1, f2 f32.Point, angle float32) {
 	var (
 		p clip.Path
@@ -73,12 +75,10 @@ func (qs *strokeQuads) arc(f1, f2 f32.Point, angle float32) {
 	end := len(o.Data())
 	raw := o.Data()[beg:end]
 
-	for qi := 0; len(raw) >= (ops.QuadSize + 4); qi++ {
+	for len(raw) >= (ops.QuadSize + 4) {
 		quad := ops.DecodeQuad(raw[4:])
 		raw = raw[ops.QuadSize+4:]
-		*qs = append(*qs, strokeQuad{
-			quad: quad,
-		})
+		*qs = append(*qs, strokeQuad{quad: quad})
 	}
 }
 
@@ -113,28 +113,215 @@ func (qs strokeQuads) stroke(width float32, sty clip.StrokeStyle) strokeQuads {
 	)
 
 	for _, ps := range qs.split() {
-		rhs, lhs := ps.offset(hw, sty)
-		switch lhs {
-		case nil:
-			o = o.append(rhs)
-		default:
-			// Closed path.
-			// Inner path should go opposite direction to cancel outer path.
-			switch {
-			case ps.ccw():
-				lhs = lhs.reverse()
+		for _, ds := range ps.dash(sty) {
+			if len(ds) == 0 {
+				continue
+			}
+			rhs, lhs := ds.offset(hw, sty)
+			switch lhs {
+			case nil:
 				o = o.append(rhs)
-				o = o.append(lhs)
 			default:
-				rhs = rhs.reverse()
-				o = o.append(lhs)
-				o = o.append(rhs)
+				// Closed path.
+				// Inner path should go opposite direction to cancel outer path.
+				switch {
+				case ds.ccw():
+					lhs = lhs.reverse()
+					o = o.append(rhs)
+					o = o.append(lhs)
+				default:
+					rhs = rhs.reverse()
+					o = o.append(lhs)
+					o = o.append(rhs)
+				}
 			}
 		}
 	}
 	return o
 }
 
+// AAVertex is a vertex of an anti-aliased stroke mesh, as produced by
+// strokeAA. Dist is the vertex's signed distance to the stroke's centerline,
+// as a fraction of the half-width, in [-1, 1]; a fragment shader turns it
+// into a coverage value with something like saturate(0.5 - dist*px_scale).
+type AAVertex struct {
+	Pos  f32.Point
+	Dist float32
+}
+
+// strokeAA is a preparatory, CPU-side-only piece of what a future
+// triangle-strip AA stroke renderer will need: it triangulates the stroke
+// of qs directly into a mesh of AAVertex, instead of the quads consumed by
+// the coverage rasterizer that stroke produces. Each centerline sample
+// contributes three vertices - the left edge (Dist -1), the centerline
+// itself (Dist 0), and the right edge (Dist +1) - so every triangle only
+// has to interpolate across one unit of Dist, regardless of stroke width.
+//
+// It is not wired to anything: there is no caller outside this package's
+// tests. Do not treat it as satisfying a request for an AA stroke renderer -
+// that request stays open until a caller exists. Still outstanding, and
+// required before one can:
+//   - a fragment shader that consumes AAVertex (Dist -> coverage);
+//   - an op encoding/GPU program wiring to carry the mesh to that shader;
+//   - real join/cap fan geometry built from sty.Join/sty.Cap, rather than
+//     the current averaged-centerline-tangent approximation;
+//   - a benchmark harness comparing this path to the coverage rasterizer.
+//
+// What it does get right, and what its tests check, is the centerline
+// triangulation itself, including stitching closed contours back together
+// at the seam.
+func (qs strokeQuads) strokeAA(width float32, sty clip.StrokeStyle) (verts []AAVertex, idx []uint32) {
+	hw := 0.5 * width
+	for _, ps := range qs.split() {
+		for _, ds := range ps.dash(sty) {
+			if len(ds) == 0 {
+				continue
+			}
+			verts, idx = ds.strokeAAContour(hw, verts, idx)
+		}
+	}
+	return verts, idx
+}
+
+// strokeAAContour appends the triangle mesh for the single contour ps to
+// verts and idx, returning the extended slices.
+func (ps strokeQuads) strokeAAContour(hw float32, verts []AAVertex, idx []uint32) ([]AAVertex, []uint32) {
+	const tolerance = 0.1
+
+	pts := []f32.Point{ps[0].quad.From}
+	for _, q := range ps {
+		var flat strokeQuads
+		flat = flattenQuadBezierUniform(flat, q.quad.From, q.quad.Ctrl, q.quad.To, 0, tolerance)
+		for _, f := range flat {
+			pts = append(pts, f.quad.To)
+		}
+	}
+	if len(pts) < 2 {
+		return verts, idx
+	}
+	// A closed contour repeats its start point as its end point; share a
+	// single wrap-around tangent between them instead of the one-sided
+	// tangent each end would otherwise get on its own, so the mesh doesn't
+	// crack open at the seam.
+	closed := len(pts) >= 3 && pts[0] == pts[len(pts)-1]
+
+	base := uint32(len(verts))
+	for i, p := range pts {
+		var tangent f32.Point
+		switch {
+		case closed && (i == 0 || i == len(pts)-1):
+			tangent = pts[1].Sub(pts[len(pts)-2])
+		case i == 0:
+			tangent = pts[1].Sub(pts[0])
+		case i == len(pts)-1:
+			tangent = pts[i].Sub(pts[i-1])
+		default:
+			// Average the tangents on either side, so a join doesn't pinch
+			// the mesh shut on its inner side.
+			tangent = pts[i+1].Sub(pts[i-1])
+		}
+		n := normPt(rot90CW(tangent), hw)
+		verts = append(verts,
+			AAVertex{Pos: p.Sub(n), Dist: -1},
+			AAVertex{Pos: p, Dist: 0},
+			AAVertex{Pos: p.Add(n), Dist: 1},
+		)
+	}
+
+	for i := 0; i < len(pts)-1; i++ {
+		v0 := base + uint32(i)*3
+		v1 := base + uint32(i+1)*3
+		idx = append(idx,
+			v0, v0+1, v1, v0+1, v1+1, v1, // left half: Dist -1..0
+			v0+1, v0+2, v1+1, v0+2, v1+2, v1+1, // right half: Dist 0..1
+		)
+	}
+
+	return verts, idx
+}
+
+// dash splits the contour ps into the sub-contours covered by the "on"
+// segments of sty.Dashes, measured by arc length and offset by
+// sty.DashPhase. It returns []strokeQuads{ps} unchanged when sty.Dashes is
+// empty. A dash that straddles the seam of a closed contour is stitched back
+// together into a single sub-contour spanning the wrap-around, rather than
+// being split in two there. That stitched sub-contour's start (the last
+// on-segment's start) and end (the first on-segment's end) are still two
+// distinct points, so offset() correctly treats it as open and caps both -
+// same as any other dash.
+func (ps strokeQuads) dash(sty clip.StrokeStyle) []strokeQuads {
+	if len(sty.Dashes) == 0 || len(ps) == 0 {
+		return []strokeQuads{ps}
+	}
+
+	pattern := sty.Dashes
+	if len(pattern)%2 != 0 {
+		pattern = append(append([]float32{}, pattern...), pattern...)
+	}
+	var period float32
+	for _, d := range pattern {
+		period += d
+	}
+	if period <= 0 {
+		return []strokeQuads{ps}
+	}
+	closed := ps[0].quad.From == ps[len(ps)-1].quad.To
+
+	phase := float32(math.Mod(float64(sty.DashPhase), float64(period)))
+	if phase < 0 {
+		phase += period
+	}
+	idx := 0
+	for phase >= pattern[idx] {
+		phase -= pattern[idx]
+		idx = (idx + 1) % len(pattern)
+	}
+	on, remain := idx%2 == 0, pattern[idx]-phase
+	firstOn := on
+
+	var out []strokeQuads
+	var cur strokeQuads
+	emit := func(p0, ctrl, p1 f32.Point) {
+		if on {
+			cur = append(cur, strokeQuad{quad: ops.Quad{From: p0, Ctrl: ctrl, To: p1}})
+		}
+	}
+	toggle := func() {
+		if on && len(cur) > 0 {
+			out = append(out, cur)
+			cur = nil
+		}
+		on = !on
+		idx = (idx + 1) % len(pattern)
+		remain = pattern[idx]
+	}
+
+	for _, q := range ps {
+		p0, ctrl, p1 := q.quad.From, q.quad.Ctrl, q.quad.To
+		length := quadBezierArcLength(p0, ctrl, p1, 0, 1)
+		for length > remain {
+			t := quadBezierArcParam(p0, ctrl, p1, length, remain)
+			b0, b1, b2, a0, a1, a2 := quadBezierSplit(p0, ctrl, p1, t)
+			emit(b0, b1, b2)
+			toggle()
+			p0, ctrl, p1 = a0, a1, a2
+			length = quadBezierArcLength(p0, ctrl, p1, 0, 1)
+		}
+		remain -= length
+		emit(p0, ctrl, p1)
+	}
+	if on && len(cur) > 0 {
+		out = append(out, cur)
+	}
+
+	if closed && firstOn && on && len(out) > 1 {
+		out[0] = append(append(strokeQuads{}, out[len(out)-1]...), out[0]...)
+		out = out[:len(out)-1]
+	}
+
+	return out
+}
+
 // offset returns the right-hand and left-hand sides of the path, offset by
 // the half-width hw.
 // The stroke style sty handles how segments are joined and ends are capped.
@@ -270,8 +457,7 @@ func strokePathNorm(p0, p1, p2 f32.Point, t, d float32) f32.Point {
 	panic("impossible")
 }
 
-func rot90CW(p f32.Point) f32.Point  { return f32.Pt(+p.Y, -p.X) }
-func rot90CCW(p f32.Point) f32.Point { return f32.Pt(-p.Y, +p.X) }
+func rot90CW(p f32.Point) f32.Point { return f32.Pt(+p.Y, -p.X) }
 
 func normPt(p f32.Point, l float32) f32.Point {
 	d := math.Hypot(float64(p.X), float64(p.Y))
@@ -341,16 +527,324 @@ func quadBezierD2(p0, p1, p2 f32.Point, t float32) f32.Point {
 	return p.Mul(2)
 }
 
+// gauss7Nodes and gauss7Weights are the abscissas and weights of the 7-point
+// Gauss-Legendre quadrature rule on [-1, 1].
+var (
+	gauss7Nodes = [...]float64{
+		-0.9491079123427585, -0.7415311855993945, -0.4058451513773972, 0,
+		0.4058451513773972, 0.7415311855993945, 0.9491079123427585,
+	}
+	gauss7Weights = [...]float64{
+		0.1294849661688697, 0.2797053914892766, 0.3818300505051189, 0.4179591836734694,
+		0.3818300505051189, 0.2797053914892766, 0.1294849661688697,
+	}
+)
+
+// quadBezierArcLength returns the arc length of the quadratic Bézier curve
+// (p0, ctrl, p1) between t0 and t1, computed by Legendre-Gauss quadrature of
+// the curve's speed |B'(t)|.
+func quadBezierArcLength(p0, ctrl, p1 f32.Point, t0, t1 float32) float32 {
+	var sum float64
+	mid, half := float64(t0+t1)/2, float64(t1-t0)/2
+	for i, x := range gauss7Nodes {
+		d := quadBezierD1(p0, ctrl, p1, float32(half*x+mid))
+		sum += gauss7Weights[i] * math.Hypot(float64(d.X), float64(d.Y))
+	}
+	return float32(sum * half)
+}
+
+// quadBezierArcParam returns the parameter t such that the arc length of
+// (p0, ctrl, p1) from 0 to t equals s, where length is the curve's total arc
+// length (ie: quadBezierArcLength(p0, ctrl, p1, 0, 1)). It uses Newton's
+// method, bisecting whenever an iteration would step outside the interval
+// known to bracket the root, and iterates to a residual tolerance rather
+// than a fixed step count: a run of bisection fallbacks (eg. on a segment
+// whose speed vanishes partway through) converges far slower than Newton's
+// usual quadratic rate, and a fixed 8 iterations isn't enough of a floor for
+// that case - on a dashed path built of many segments, the shortfall on
+// each split accumulates into a visible drift of the dash pattern.
+func quadBezierArcParam(p0, ctrl, p1 f32.Point, length, s float32) float32 {
+	const (
+		maxIter = 24
+		eps     = 1e-4
+	)
+	lo, hi := float32(0), float32(1)
+	t := s / length
+	for i := 0; i < maxIter; i++ {
+		f := quadBezierArcLength(p0, ctrl, p1, 0, t) - s
+		if f < 0 {
+			lo = t
+		} else {
+			hi = t
+		}
+		if float32(math.Abs(float64(f))) <= eps {
+			break
+		}
+		d := quadBezierD1(p0, ctrl, p1, t)
+		speed := float32(math.Hypot(float64(d.X), float64(d.Y)))
+		next := t
+		if speed > 1e-6 {
+			next -= f / speed
+		}
+		if next <= lo || next >= hi {
+			next = (lo + hi) / 2
+		}
+		t = next
+	}
+	return t
+}
+
+// strokeQuadBezier offsets the quadratic Bézier curve described by state by
+// d, flattening the result to the given tolerance.
+//
+// Near a cusp - where the local radius of curvature drops below the offset
+// distance - a straight offset-then-flatten would fold back on itself. Hain
+// et al. handle this by subdividing the source curve at the point(s) where
+// |curvature| == hw and replacing the offset of the cusp-prone piece(s) with
+// a short arc of radius hw, which strokeQuadBezier does here via cuspArc.
+//
+// That only holds on the concave side, the one offsetting toward the centre
+// of curvature: the convex side offsets cleanly to radius R+hw however small
+// R gets, and replacing it with cuspArc would pinch it down to radius hw.
+// strokePathCurv's sign tells them apart - it's negative wherever the curve
+// bends toward +d's normal direction - so a side is cusp-prone only when
+// |curvature| < hw *and* d and the signed radius carry opposite signs (d *
+// r < 0).
+//
+// |curvature| isn't monotonic along the curve: since a quadratic Bézier's
+// second derivative is constant, its first derivative's magnitude (and so
+// its curvature) is a unimodal function of t, with at most one interior
+// extremum (see curvatureMinParam). So a cusp can show up three ways: both
+// endpoints already inside the cusp radius (curvatureMinParam can only be
+// further inside, by unimodality - no need to look for it), one endpoint
+// inside, or - the case a plain endpoint check misses - both endpoints
+// outside while the curve still dips inside partway through.
 func strokeQuadBezier(state strokeState, d, flatness float32) strokeQuads {
-	// Gio strokes are only quadratic Bézier curves, w/o any inflection point.
-	// So we just have to flatten them.
-	var qs strokeQuads
-	return flattenQuadBezier(qs, state.p0, state.ctl, state.p1, d, flatness)
+	var (
+		qs  strokeQuads
+		hw  = float32(math.Abs(float64(d)))
+		p0  = state.p0
+		ctl = state.ctl
+		p1  = state.p1
+
+		r0Inside = float32(math.Abs(float64(state.r0))) < hw && d*state.r0 < 0
+		r1Inside = float32(math.Abs(float64(state.r1))) < hw && d*state.r1 < 0
+	)
+
+	switch {
+	case r0Inside && r1Inside:
+		qs.cuspArc(p0, ctl, p1, d, hw)
+		return qs
+
+	case r0Inside:
+		t0 := curvatureBoundary(p0, ctl, p1, hw, 0, 1)
+		c0, cc, c1, rest0, restc, rest1 := quadBezierSplit(p0, ctl, p1, t0)
+		qs.cuspArc(c0, cc, c1, d, hw)
+		return flattenQuadBezier(qs, rest0, restc, rest1, d, flatness)
+
+	case r1Inside:
+		t1 := curvatureBoundary(p0, ctl, p1, hw, 1, 0)
+		c0, cc, c1, rest0, restc, rest1 := quadBezierSplit(p0, ctl, p1, t1)
+		qs = flattenQuadBezier(qs, c0, cc, c1, d, flatness)
+		qs.cuspArc(rest0, restc, rest1, d, hw)
+		return qs
+	}
+
+	if tMin, ok := curvatureMinParam(p0, ctl, p1); ok {
+		if rMin := strokePathCurv(p0, ctl, p1, tMin); float32(math.Abs(float64(rMin))) < hw && d*rMin < 0 {
+			ta := curvatureBoundary(p0, ctl, p1, hw, tMin, 0)
+			tb := curvatureBoundary(p0, ctl, p1, hw, tMin, 1)
+
+			a0, ac, a1, mid0, midc, mid1 := quadBezierSplit(p0, ctl, p1, ta)
+			qs = flattenQuadBezier(qs, a0, ac, a1, d, flatness)
+
+			tbRel := (tb - ta) / (1 - ta)
+			b0, bc, b1, c0, cc, c1 := quadBezierSplit(mid0, midc, mid1, tbRel)
+			qs.cuspArc(b0, bc, b1, d, hw)
+			return flattenQuadBezier(qs, c0, cc, c1, d, flatness)
+		}
+	}
+
+	return flattenQuadBezier(qs, p0, ctl, p1, d, flatness)
+}
+
+// curvatureMinParam returns the parameter t in (0, 1) at which |curvature|
+// reaches an interior minimum, and whether one exists in range. A quadratic
+// Bézier's second derivative is constant, so d1(t) = 2(1-t)(ctl-p0) +
+// 2t(p1-ctl) traces a straight line in velocity space as t goes from 0 to 1,
+// making |d1(t)| - and so |curvature|, since curvature is |d1(t)|^3 over a
+// constant - a convex, unimodal function of t. Its unique minimum, found by
+// setting the derivative of |d1(t)|^2 to zero, is interior only if the curve
+// isn't already monotonically speeding up or slowing down throughout.
+func curvatureMinParam(p0, ctl, p1 f32.Point) (float32, bool) {
+	const eps = 1e-9
+	var (
+		d01   = ctl.Sub(p0)
+		d12   = p1.Sub(ctl)
+		denom = dotPt(d01, d01) - 2*dotPt(d01, d12) + dotPt(d12, d12)
+	)
+	if float32(math.Abs(float64(denom))) < eps {
+		return 0, false
+	}
+	t := (dotPt(d01, d01) - dotPt(d01, d12)) / denom
+	if t <= 0 || t >= 1 {
+		return 0, false
+	}
+	return t, true
+}
+
+// curvatureBoundary returns the parameter between insideT (where the local
+// radius of curvature is known to be below hw) and outsideT (where it is
+// not) at which |curvature| == hw, found by bisection. Although |curvature|
+// isn't monotonic over the whole curve (see curvatureMinParam), it is
+// monotonic between any two parameters on the same side of its one interior
+// extremum, which insideT and outsideT always are here, so the boundary
+// between them is unique.
+func curvatureBoundary(p0, ctl, p1 f32.Point, hw, insideT, outsideT float32) float32 {
+	for i := 0; i < 24; i++ {
+		mid := (insideT + outsideT) / 2
+		if r := float32(math.Abs(float64(strokePathCurv(p0, ctl, p1, mid)))); r < hw {
+			insideT = mid
+		} else {
+			outsideT = mid
+		}
+	}
+	return (insideT + outsideT) / 2
+}
+
+// cuspArc replaces the offset of the sub-curve (p0, ctl, p1) - whose local
+// radius of curvature is below hw throughout - with a short arc of radius hw
+// between its two offset endpoints, avoiding the self-intersecting loop a
+// straight offset would trace there.
+func (qs *strokeQuads) cuspArc(p0, ctl, p1 f32.Point, d, hw float32) {
+	var (
+		n0    = strokePathNorm(p0, ctl, p1, 0, d)
+		n1    = strokePathNorm(p0, ctl, p1, 1, d)
+		start = p0.Add(n0)
+		end   = p1.Add(n1)
+		// The curve's own midpoint isn't hw from start/end in general; it
+		// only picks which of the two equidistant candidates to use.
+		hint   = quadBezierSample(p0, ctl, p1, 0.5)
+		center = cuspArcCenter(start, end, hw, hint)
+	)
+	if len(*qs) == 0 {
+		*qs = append(*qs, strokeQuad{quad: ops.Quad{From: start, Ctrl: start, To: start}})
+	} else {
+		qs.lineTo(start)
+	}
+	c0, c1 := start.Sub(center), end.Sub(center)
+	angle := float32(math.Atan2(float64(perpDot(c0, c1)), float64(dotPt(c0, c1))))
+	qs.arc(center.Sub(qs.pen()), center.Sub(qs.pen()), angle)
+}
+
+// cuspArcCenter returns the point that is hw from both a and b, on the side
+// of the line a-b closer to hint. When a and b are more than 2*hw apart (the
+// offset endpoints can't actually lie on a common circle of radius hw - a
+// sign that hw is larger than the curve's local scale), it falls back to the
+// point on their perpendicular bisector closest to being hw from both.
+func cuspArcCenter(a, b f32.Point, hw float32, hint f32.Point) f32.Point {
+	var (
+		mid  = a.Add(b).Mul(0.5)
+		ab   = b.Sub(a)
+		half = float32(math.Hypot(float64(ab.X), float64(ab.Y))) / 2
+	)
+	if half > hw {
+		half = hw
+	}
+	h := float32(math.Sqrt(float64(hw*hw - half*half)))
+	perp := rot90CW(ab)
+	if l := float32(math.Hypot(float64(perp.X), float64(perp.Y))); l > 1e-9 {
+		perp = perp.Mul(1 / l)
+	}
+	c0, c1 := mid.Add(perp.Mul(h)), mid.Sub(perp.Mul(h))
+	if distSqPt(c0, hint) <= distSqPt(c1, hint) {
+		return c0
+	}
+	return c1
+}
+
+func distSqPt(p, q f32.Point) float32 {
+	d := p.Sub(q)
+	return d.X*d.X + d.Y*d.Y
 }
 
 // flattenQuadBezier splits a Bézier quadratic curve into linear sub-segments,
 // themselves also encoded as Bézier (degenerate, flat) quadratic curves.
+//
+// It uses Raph Levien's parabola approximation: the curve is mapped onto the
+// canonical parabola y = x², where it has a closed-form, optimal count of
+// equal-error subdivisions, spaced evenly in the parabola's arc-parameter
+// u = asinh(x). See:
+//
+//	https://raphlinus.github.io/graphics/curves/2019/12/23/flatten-quadbez.html
+//
+// It falls back to flattenQuadBezierUniform when the control triangle is
+// degenerate (colinear control points, or a vanishing second derivative),
+// for which the parabola mapping is undefined.
 func flattenQuadBezier(qs strokeQuads, p0, p1, p2 f32.Point, d, flatness float32) strokeQuads {
+	const eps = 1e-9
+
+	var (
+		d01    = p1.Sub(p0)
+		d12    = p2.Sub(p1)
+		ddp    = p2.Sub(p1.Mul(2)).Add(p0) // The (constant) second derivative, halved: P2 - 2·P1 + P0.
+		cross  = perpDot(d01, d12)
+		ddpLen = float32(math.Hypot(float64(ddp.X), float64(ddp.Y)))
+	)
+	if float32(math.Abs(float64(cross))) < eps || ddpLen < eps {
+		return flattenQuadBezierUniform(qs, p0, p1, p2, d, flatness)
+	}
+
+	// x0, x1 are the curve's endpoints, mapped onto the canonical parabola.
+	x0 := dotPt(d01, ddp) / cross
+	x1 := dotPt(d12, ddp) / cross
+	if x1 == x0 {
+		return flattenQuadBezierUniform(qs, p0, p1, p2, d, flatness)
+	}
+
+	// scale converts an error measured in the canonical parabola's arc length
+	// back into actual curve-space error: it is the ratio between how fast
+	// the original curve moves (ddpLen) and how much of the x0..x1 range that
+	// motion is spread across, relative to the curvature term (cross). Using
+	// the raw |ddp| in its place (as an earlier version of this function did)
+	// leaves the subdivision count blind to that spread, and badly
+	// under-tessellates long, shallow curves.
+	scale := float32(math.Abs(float64(cross))) / (ddpLen * float32(math.Abs(float64(x1-x0))))
+
+	// subdivScale is an empirical safety margin on top of the textbook 0.5
+	// coefficient: this function samples the exact parabola arc length
+	// (asinh/sinh) rather than a cheap rational approximation of it, and 0.5
+	// alone was found, by dense-sampling the flattened output against the
+	// analytic curve across a range of shapes and tolerances (see
+	// TestFlattenQuadBezierTolerance), to sometimes under-shoot the
+	// requested flatness by up to ~20%.
+	const subdivScale = 0.65
+	n := int(math.Ceil(subdivScale * math.Abs(float64(x1-x0)) * math.Sqrt(float64(scale)/float64(flatness))))
+	if n < 1 {
+		n = 1
+	}
+
+	u0, u1 := math.Asinh(float64(x0)), math.Asinh(float64(x1))
+	tPrev := float32(0)
+	for i := 1; i < n; i++ {
+		u := u0 + (u1-u0)*float64(i)/float64(n)
+		x := float32(math.Sinh(u))
+		t := (x - x0) / (x1 - x0) // Absolute parameter, along the original curve.
+
+		local := (t - tPrev) / (1 - tPrev)
+		var q0, q1, q2 f32.Point
+		q0, q1, q2, p0, p1, p2 = quadBezierSplit(p0, p1, p2, local)
+		qs.addLine(q0, q1, q2, 0, d)
+		tPrev = t
+	}
+	qs.addLine(p0, p1, p2, 1, d)
+	return qs
+}
+
+// flattenQuadBezierUniform is the Hain et al. error-estimate flattening used
+// before flattenQuadBezier learned the parabola mapping; it remains as the
+// fallback for degenerate control triangles.
+func flattenQuadBezierUniform(qs strokeQuads, p0, p1, p2 f32.Point, d, flatness float32) strokeQuads {
 	var t float32
 	for t < 1 {
 		s2 := float64((p2.X-p0.X)*(p1.Y-p0.Y) - (p2.Y-p0.Y)*(p1.X-p0.X))
@@ -414,56 +908,56 @@ func quadBezierSplit(p0, p1, p2 f32.Point, t float32) (f32.Point, f32.Point, f32
 }
 
 // strokePathJoin joins the two paths rhs and lhs, according to the provided
-// stroke style sty.
+// stroke style sty. The join (and cap, below) geometry itself now lives in
+// op/clip as a pluggable clip.Joiner, so that callers can supply their own;
+// strokePathJoin only bridges the gpu package's internal quad representation
+// to the one a clip.Joiner operates on. A nil sty.Join (the zero value of
+// clip.StrokeStyle) falls back to clip.BevelJoin, so a caller that only
+// cares about width doesn't have to also spell out a join.
 func strokePathJoin(sty clip.StrokeStyle, rhs, lhs *strokeQuads, hw float32, pivot, n0, n1 f32.Point, r0, r1 float32) {
-	strokePathBevelJoin(rhs, lhs, hw, pivot, n0, n1, r0, r1)
-}
-
-func strokePathBevelJoin(rhs, lhs *strokeQuads, hw float32, pivot, n0, n1 f32.Point, r0, r1 float32) {
-
-	rp := pivot.Add(n1)
-	lp := pivot.Sub(n1)
-
-	rhs.lineTo(rp)
-	lhs.lineTo(lp)
+	join := sty.Join
+	if join == nil {
+		join = clip.BevelJoin
+	}
+	rcq, lcq := rhs.clipTail(), lhs.clipTail()
+	join.Join(&rcq, &lcq, hw, pivot, n0, n1, r0, r1)
+	rhs.appendClipTail(rcq)
+	lhs.appendClipTail(lcq)
 }
 
-// strokePathCap caps the provided path qs, according to the provided stroke style sty.
+// strokePathCap caps the provided path qs, according to the provided stroke
+// style sty, by bridging to sty.Cap (a clip.Capper). A nil sty.Cap (the zero
+// value of clip.StrokeStyle) falls back to clip.FlatCap, so a caller that
+// only cares about width doesn't have to also spell out a cap.
 func strokePathCap(sty clip.StrokeStyle, qs *strokeQuads, hw float32, pivot, n0 f32.Point) {
-	switch sty.Cap {
-	case clip.FlatCap:
-		strokePathFlatCap(qs, hw, pivot, n0)
-	case clip.SquareCap:
-		strokePathSquareCap(qs, hw, pivot, n0)
-	case clip.RoundCap:
-		strokePathRoundCap(qs, hw, pivot, n0)
-	default:
-		panic("impossible")
+	capper := sty.Cap
+	if capper == nil {
+		capper = clip.FlatCap
 	}
+	cq := qs.clipTail()
+	capper.Cap(&cq, hw, pivot, n0)
+	qs.appendClipTail(cq)
 }
 
-// strokePathFlatCap caps the start or end of a path with a flat cap.
-func strokePathFlatCap(qs *strokeQuads, hw float32, pivot, n0 f32.Point) {
-	end := pivot.Sub(n0)
-	qs.lineTo(end)
-}
-
-// strokePathSquareCap caps the start or end of a path with a square cap.
-func strokePathSquareCap(qs *strokeQuads, hw float32, pivot, n0 f32.Point) {
-	var (
-		e       = pivot.Add(rot90CCW(n0))
-		corner1 = e.Add(n0)
-		corner2 = e.Sub(n0)
-		end     = pivot.Sub(n0)
-	)
-
-	qs.lineTo(corner1)
-	qs.lineTo(corner2)
-	qs.lineTo(end)
+// clipTail returns a clip.StrokeQuads seeded with only qs's trailing
+// segment - the minimum a clip.Joiner or clip.Capper needs, since both only
+// ever read the current pen position (the last segment's endpoint) and then
+// append new geometry after it. Converting the whole accumulated qs on every
+// join or cap, as an earlier version of this function did, copies it in
+// full each time; since offset calls this once per segment boundary while
+// rhs/lhs are still growing, that turns an O(1)-amortized append per join
+// into an O(n) copy per join, O(n²) per stroked contour.
+func (qs *strokeQuads) clipTail() clip.StrokeQuads {
+	last := (*qs)[len(*qs)-1].quad
+	return clip.StrokeQuads{Segments: []clip.StrokeSegment{
+		{From: last.From, Ctrl: last.Ctrl, To: last.To},
+	}}
 }
 
-// strokePathRoundCap caps the start or end of a path with a round cap.
-func strokePathRoundCap(qs *strokeQuads, hw float32, pivot, n0 f32.Point) {
-	c := pivot.Sub(qs.pen())
-	qs.arc(c, c, math.Pi)
+// appendClipTail appends the segments a clip.Joiner or clip.Capper added
+// after the seed segment clipTail provided, onto qs.
+func (qs *strokeQuads) appendClipTail(cq clip.StrokeQuads) {
+	for _, s := range cq.Segments[1:] {
+		*qs = append(*qs, strokeQuad{quad: ops.Quad{From: s.From, Ctrl: s.Ctrl, To: s.To}})
+	}
 }
@@ -0,0 +1,473 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"math"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/internal/ops"
+	"gioui.org/op/clip"
+)
+
+// flattenShapes are representative of the quadratic Bézier segments typical
+// UI paths decompose into: a wide shallow arc (e.g. a large rounded rect
+// corner), a tight curve (a small rounded corner or icon glyph), a long
+// near-straight curve, and an asymmetric one.
+var flattenShapes = map[string][3]f32.Point{
+	"shallow wide": {{X: 0, Y: 0}, {X: 500, Y: 10}, {X: 1000, Y: 0}},
+	"tight":        {{X: 0, Y: 0}, {X: 50, Y: 100}, {X: 100, Y: 0}},
+	"long shallow": {{X: 0, Y: 0}, {X: 2000, Y: 5}, {X: 4000, Y: 0}},
+	"asymmetric":   {{X: 0, Y: 0}, {X: 300, Y: 100}, {X: 1000, Y: 0}},
+}
+
+// maxDeviation returns the largest distance from any sample of the curve
+// (p0, ctl, p1) to the polyline traced by qs's quad endpoints.
+func maxDeviation(p0, ctl, p1 f32.Point, qs strokeQuads) float32 {
+	pts := make([]f32.Point, 0, len(qs)+1)
+	if len(qs) > 0 {
+		pts = append(pts, qs[0].quad.From)
+	}
+	for _, q := range qs {
+		pts = append(pts, q.quad.To)
+	}
+
+	var maxd float32
+	const samples = 500
+	for i := 0; i <= samples; i++ {
+		t := float32(i) / samples
+		p := quadBezierSample(p0, ctl, p1, t)
+
+		best := float32(math.MaxFloat32)
+		for i := 0; i+1 < len(pts); i++ {
+			d := distToSegment(p, pts[i], pts[i+1])
+			if d < best {
+				best = d
+			}
+		}
+		if best > maxd {
+			maxd = best
+		}
+	}
+	return maxd
+}
+
+func distToSegment(p, a, b f32.Point) float32 {
+	ab := b.Sub(a)
+	l2 := dotPt(ab, ab)
+	if l2 < 1e-12 {
+		d := p.Sub(a)
+		return float32(math.Hypot(float64(d.X), float64(d.Y)))
+	}
+	t := dotPt(p.Sub(a), ab) / l2
+	if t < 0 {
+		t = 0
+	} else if t > 1 {
+		t = 1
+	}
+	proj := a.Add(ab.Mul(t))
+	d := p.Sub(proj)
+	return float32(math.Hypot(float64(d.X), float64(d.Y)))
+}
+
+// TestFlattenQuadBezierTolerance checks that flattenQuadBezier's output
+// stays within the requested flatness of the source curve, across a range
+// of curve shapes and tolerances. Offsetting by d=0 isolates the
+// tessellation from the (separately tested) offset-normal computation, so
+// the polyline traced is a direct approximation of the curve itself.
+func TestFlattenQuadBezierTolerance(t *testing.T) {
+	for name, pts := range flattenShapes {
+		for _, flatness := range []float32{1, 0.1, 0.01} {
+			qs := flattenQuadBezier(nil, pts[0], pts[1], pts[2], 0, flatness)
+			got := maxDeviation(pts[0], pts[1], pts[2], qs)
+			// subdivScale is an empirical margin over the textbook estimate,
+			// so allow a little slack beyond the nominal flatness rather
+			// than demanding an exact bound.
+			if want := flatness * 1.5; got > want {
+				t.Errorf("%s at flatness %v: max deviation %v, want <= %v", name, flatness, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkFlattenQuadBezier(b *testing.B) {
+	for name, pts := range flattenShapes {
+		pts := pts
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = flattenQuadBezier(nil, pts[0], pts[1], pts[2], 1, 0.1)
+			}
+		})
+	}
+}
+
+// TestCuspArcCenterRadius checks cuspArcCenter's defining property directly:
+// the point it returns is hw from both a and b. cuspArc previously used the
+// curve's own t=0.5 sample as the arc center instead, which is only hw from
+// the offset endpoints when p0 happens to sit at that center.
+func TestCuspArcCenterRadius(t *testing.T) {
+	cases := []struct {
+		a, b, hint f32.Point
+		hw         float32
+	}{
+		{f32.Pt(0, 0), f32.Pt(2, 0), f32.Pt(1, 5), 3},
+		{f32.Pt(-1, -1), f32.Pt(4, 2), f32.Pt(0, 10), 5},
+		// The reviewer's regression case: offset endpoints of
+		// P0=(0,0), ctl=(1,10), P2=(2,0) at hw=3.
+		{f32.Pt(0, 0).Add(strokePathNorm(f32.Pt(0, 0), f32.Pt(1, 10), f32.Pt(2, 0), 0, 3)),
+			f32.Pt(2, 0).Add(strokePathNorm(f32.Pt(0, 0), f32.Pt(1, 10), f32.Pt(2, 0), 1, 3)),
+			quadBezierSample(f32.Pt(0, 0), f32.Pt(1, 10), f32.Pt(2, 0), 0.5), 3},
+	}
+	for _, c := range cases {
+		center := cuspArcCenter(c.a, c.b, c.hw, c.hint)
+		for _, p := range []f32.Point{c.a, c.b} {
+			d := center.Sub(p)
+			dist := float32(math.Hypot(float64(d.X), float64(d.Y)))
+			if math.Abs(float64(dist-c.hw)) > 1e-3 {
+				t.Errorf("cuspArcCenter(%v, %v, hw=%v): distance to %v = %v, want %v", c.a, c.b, c.hw, p, dist, c.hw)
+			}
+		}
+	}
+}
+
+// TestCurvatureMinParam checks the closed-form interior curvature extremum
+// against the reviewer's counterexample, where |curvature| dips well below
+// both endpoints' values partway through the segment.
+func TestCurvatureMinParam(t *testing.T) {
+	p0, ctl, p1 := f32.Pt(0, 0), f32.Pt(5, 5), f32.Pt(10, 0)
+	tMin, ok := curvatureMinParam(p0, ctl, p1)
+	if !ok {
+		t.Fatalf("curvatureMinParam reported no interior extremum for a symmetric arc")
+	}
+	if math.Abs(float64(tMin-0.5)) > 1e-3 {
+		t.Errorf("tMin = %v, want ~0.5 (the curve is symmetric about t=0.5)", tMin)
+	}
+	r0 := strokePathCurv(p0, ctl, p1, 0)
+	rMin := strokePathCurv(p0, ctl, p1, tMin)
+	if math.Abs(float64(rMin)) >= math.Abs(float64(r0)) {
+		t.Errorf("|curvature| at tMin (%v) should be smaller than at the endpoints (%v)", rMin, r0)
+	}
+}
+
+// offsetGroundTruth densely samples the naive, pointwise offset of the
+// quadratic Bézier (p0, ctl, p1) by d: the curve's own sample at t plus its
+// local normal, scaled to d. Away from a cusp this is the true offset
+// curve; strokeQuadBezier's output must track it closely there, and must
+// depart from it - onto cuspArc's arc - only on the side that would
+// otherwise fold back on itself.
+func offsetGroundTruth(p0, ctl, p1 f32.Point, d float32, samples int) []f32.Point {
+	pts := make([]f32.Point, 0, samples+1)
+	for i := 0; i <= samples; i++ {
+		t := float32(i) / float32(samples)
+		n := normPt(rot90CW(quadBezierD1(p0, ctl, p1, t)), d)
+		pts = append(pts, quadBezierSample(p0, ctl, p1, t).Add(n))
+	}
+	return pts
+}
+
+// maxDeviationFromPoints returns the largest distance from any of pts to
+// the polyline traced by qs's quad endpoints.
+func maxDeviationFromPoints(pts []f32.Point, qs strokeQuads) float32 {
+	poly := make([]f32.Point, 0, len(qs)+1)
+	if len(qs) > 0 {
+		poly = append(poly, qs[0].quad.From)
+	}
+	for _, q := range qs {
+		poly = append(poly, q.quad.To)
+	}
+
+	var maxd float32
+	for _, p := range pts {
+		best := float32(math.MaxFloat32)
+		for i := 0; i+1 < len(poly); i++ {
+			if d := distToSegment(p, poly[i], poly[i+1]); d < best {
+				best = d
+			}
+		}
+		if best > maxd {
+			maxd = best
+		}
+	}
+	return maxd
+}
+
+// TestStrokeQuadBezierInteriorCusp reproduces the reviewer's case where both
+// endpoints of a segment read as outside the cusp radius (hw=7), but the
+// curve dips well inside it partway through. Detection that only samples the
+// two endpoints takes the straight-flatten fast path and misses it entirely.
+//
+// It also checks the reviewer's pinched-outer-contour regression: for
+// P0=(0,0), ctl=(1,10), P2=(2,0) at hw=3, the centre of curvature at the
+// apex is below the curve, so +hw (toward it) is the concave, cusp-prone
+// side and -hw (away from it) is convex. offset calls strokeQuadBezier with
+// the same per-segment state for both signs, so only +hw may depart onto
+// cuspArc's arc; -hw must track the densely-sampled ground-truth offset.
+func TestStrokeQuadBezierInteriorCusp(t *testing.T) {
+	p0, ctl, p1 := f32.Pt(0, 0), f32.Pt(5, 5), f32.Pt(10, 0)
+	const hw = 7
+
+	state := strokeState{
+		p0: p0, ctl: ctl, p1: p1,
+		r0: strokePathCurv(p0, ctl, p1, 0),
+		r1: strokePathCurv(p0, ctl, p1, 1),
+	}
+	if math.Abs(float64(state.r0)) < hw || math.Abs(float64(state.r1)) < hw {
+		t.Fatalf("test fixture assumption broken: both endpoints should read outside the cusp radius (r0=%v, r1=%v)", state.r0, state.r1)
+	}
+	// +hw is the side that points toward the centre of curvature here (see
+	// TestCurvatureMinParam), so it's the one an endpoints-only check would
+	// wrongly take the straight-flatten path for.
+	qs := strokeQuadBezier(state, hw, 0.1)
+	if len(qs) < 2 {
+		t.Fatalf("strokeQuadBezier produced %d quad(s) for a segment with an interior curvature dip below hw; "+
+			"an endpoints-only cusp check would take the straight-flatten path here and miss it", len(qs))
+	}
+
+	peak0, peakCtl, peak1 := f32.Pt(0, 0), f32.Pt(1, 10), f32.Pt(2, 0)
+	const peakHW = 3
+	peakState := strokeState{
+		p0: peak0, ctl: peakCtl, p1: peak1,
+		r0: strokePathCurv(peak0, peakCtl, peak1, 0),
+		r1: strokePathCurv(peak0, peakCtl, peak1, 1),
+	}
+
+	convex := strokeQuadBezier(peakState, -peakHW, 0.01)
+	truth := offsetGroundTruth(peak0, peakCtl, peak1, -peakHW, 200)
+	if dev := maxDeviationFromPoints(truth, convex); dev > 0.05 {
+		t.Errorf("convex side (d=%v) deviates from the densely-sampled ground-truth offset by %v, want <= 0.05; "+
+			"it should flatten normally, not get pinched onto cuspArc's hw-radius arc", -peakHW, dev)
+	}
+	var apexY float32 = -math.MaxFloat32
+	for _, q := range convex {
+		if y := q.quad.To.Y; y > apexY {
+			apexY = y
+		}
+	}
+	if apexY < 7.5 {
+		t.Errorf("convex side's apex reached y=%v, want >= 7.5 (~hw above the curve's own apex at y=5); "+
+			"a radius-hw cuspArc on this side pinches it down to ~y=4.3", apexY)
+	}
+
+	// The concave side (+hw) is expected to depart from the naive offset:
+	// cuspArc replaces its middle portion with an arc of radius peakHW
+	// centred where cuspArc itself would put it.
+	start := peak0.Add(strokePathNorm(peak0, peakCtl, peak1, 0, peakHW))
+	end := peak1.Add(strokePathNorm(peak0, peakCtl, peak1, 1, peakHW))
+	hint := quadBezierSample(peak0, peakCtl, peak1, 0.5)
+	center := cuspArcCenter(start, end, peakHW, hint)
+
+	concave := strokeQuadBezier(peakState, peakHW, 0.01)
+	var closestToArc float32 = math.MaxFloat32
+	for _, q := range concave {
+		d := q.quad.To.Sub(center)
+		if dist := float32(math.Abs(float64(float32(math.Hypot(float64(d.X), float64(d.Y))) - peakHW))); dist < closestToArc {
+			closestToArc = dist
+		}
+	}
+	if closestToArc > 0.01 {
+		t.Errorf("concave side has no vertex within 0.01 of cuspArc's radius-%v arc (closest: %v); expected it to draw that arc", peakHW, closestToArc)
+	}
+
+	var convexClosestToArc float32 = math.MaxFloat32
+	for _, q := range convex {
+		d := q.quad.To.Sub(center)
+		if dist := float32(math.Abs(float64(float32(math.Hypot(float64(d.X), float64(d.Y))) - peakHW))); dist < convexClosestToArc {
+			convexClosestToArc = dist
+		}
+	}
+	if convexClosestToArc < 0.5 {
+		t.Errorf("convex side has a vertex within %v of the concave side's arc; it should bulge outward instead of sharing that arc", convexClosestToArc)
+	}
+}
+
+// straightPath builds an (unoffset) contour of straight segments through
+// pts, in the same From/Ctrl/To quad encoding strokeQuads uses elsewhere
+// (see lineTo) - a degenerate quad with Ctrl at the segment's midpoint.
+func straightPath(pts ...f32.Point) strokeQuads {
+	qs := make(strokeQuads, 0, len(pts)-1)
+	for i := 1; i < len(pts); i++ {
+		from, to := pts[i-1], pts[i]
+		qs = append(qs, strokeQuad{quad: ops.Quad{From: from, Ctrl: from.Add(to).Mul(0.5), To: to}})
+	}
+	return qs
+}
+
+// triangleWinding returns the sign of the signed area of the triangle
+// (a, b, c): positive if it winds counter-clockwise, negative if clockwise,
+// zero if degenerate.
+func triangleWinding(a, b, c f32.Point) float32 {
+	ab, ac := b.Sub(a), c.Sub(a)
+	return ab.X*ac.Y - ab.Y*ac.X
+}
+
+// TestStrokeAAContourMesh checks strokeAAContour's two invariants on a known
+// open contour: every Dist -1/+1 vertex sits exactly hw from its Dist 0
+// counterpart (the mesh is the requested width throughout), and every
+// triangle in the index buffer winds the same way (the strip never folds
+// over itself). strokeAA has no caller yet (see its doc comment), so
+// nothing else exercises this code.
+func TestStrokeAAContourMesh(t *testing.T) {
+	const hw = 2
+	ps := straightPath(f32.Pt(0, 0), f32.Pt(10, 0), f32.Pt(10, 10))
+
+	verts, idx := ps.strokeAAContour(hw, nil, nil)
+
+	const wantPts = 3
+	if got := len(verts); got != wantPts*3 {
+		t.Fatalf("got %d vertices, want %d (3 per centerline point)", got, wantPts*3)
+	}
+	if got := len(idx); got != (wantPts-1)*12 {
+		t.Fatalf("got %d indices, want %d (12 per segment)", got, (wantPts-1)*12)
+	}
+
+	for i := 0; i < len(verts); i += 3 {
+		left, center, right := verts[i], verts[i+1], verts[i+2]
+		if left.Dist != -1 || center.Dist != 0 || right.Dist != 1 {
+			t.Errorf("vertex triple %d has Dist %v, %v, %v, want -1, 0, 1", i/3, left.Dist, center.Dist, right.Dist)
+		}
+		for _, v := range []AAVertex{left, right} {
+			d := v.Pos.Sub(center.Pos)
+			if dist := float32(math.Hypot(float64(d.X), float64(d.Y))); math.Abs(float64(dist-hw)) > 1e-3 {
+				t.Errorf("vertex triple %d: edge vertex %v is %v from the centerline vertex, want %v", i/3, v.Pos, dist, hw)
+			}
+		}
+	}
+
+	var sign float32
+	for i := 0; i+2 < len(idx); i += 3 {
+		w := triangleWinding(verts[idx[i]].Pos, verts[idx[i+1]].Pos, verts[idx[i+2]].Pos)
+		switch {
+		case math.Abs(float64(w)) < 1e-6:
+			t.Errorf("triangle %d is degenerate", i/3)
+		case sign == 0:
+			sign = w
+		case w*sign < 0:
+			t.Errorf("triangle %d winds the opposite way (%v) from the rest of the mesh (%v); the strip folded over itself", i/3, w, sign)
+		}
+	}
+}
+
+// TestStrokeAAContourClosedSeam checks that a closed contour shares a single
+// wrap-around tangent between its repeated start/end point (see
+// strokeAAContour's "closed" comment), rather than cracking the mesh open
+// with two different one-sided tangents there.
+func TestStrokeAAContourClosedSeam(t *testing.T) {
+	const hw = 2
+	square := straightPath(f32.Pt(0, 0), f32.Pt(10, 0), f32.Pt(10, 10), f32.Pt(0, 10), f32.Pt(0, 0))
+
+	verts, _ := square.strokeAAContour(hw, nil, nil)
+	if len(verts) < 6 {
+		t.Fatalf("got %d vertices, too few to check the seam", len(verts))
+	}
+
+	// The contour's repeated start/end point gets its own vertex triple at
+	// each end of verts; both triples sit at the same centerline position.
+	firstCenter, lastCenter := verts[1], verts[len(verts)-2]
+	if firstCenter.Pos != lastCenter.Pos {
+		t.Fatalf("seam's centerline point differs between start and end: %v vs %v", firstCenter.Pos, lastCenter.Pos)
+	}
+	// Same wrap-around tangent at both ends of the seam means the same
+	// normal, so the Dist -1 edge vertices line up rather than forking.
+	firstEdge, lastEdge := verts[0], verts[len(verts)-3]
+	if d := firstEdge.Pos.Sub(lastEdge.Pos); d.X != 0 || d.Y != 0 {
+		t.Errorf("seam's Dist -1 vertex drifted by %v between the contour's start and end", d)
+	}
+}
+
+// approxEqualPt reports whether a and b are within eps of each other, to
+// tolerate the residual from quadBezierArcParam's iterative root-find.
+func approxEqualPt(a, b f32.Point, eps float32) bool {
+	d := a.Sub(b)
+	return float32(math.Hypot(float64(d.X), float64(d.Y))) <= eps
+}
+
+// TestDashOnOffPhase checks dash's basic on/off splitting against a
+// straight path, where arc length is exact and so the split points are
+// knowable in closed form: DashPhase=3 into pattern [4,2] (period 6) starts
+// 1 unit into the first "on" run, so on a length-20 path the on-segments
+// land at [0,1], [3,7], [9,13], [15,19]. It also checks that none of them
+// come back closed, since offset caps each independently of the others.
+func TestDashOnOffPhase(t *testing.T) {
+	ps := straightPath(f32.Pt(0, 0), f32.Pt(20, 0))
+	sty := clip.StrokeStyle{Dashes: []float32{4, 2}, DashPhase: 3}
+
+	out := ps.dash(sty)
+	want := [][2]float32{{0, 1}, {3, 7}, {9, 13}, {15, 19}}
+	if len(out) != len(want) {
+		t.Fatalf("got %d dash segments, want %d", len(out), len(want))
+	}
+	const eps = 1e-3
+	for i, w := range want {
+		from, to := out[i][0].quad.From, out[i][len(out[i])-1].quad.To
+		wantFrom, wantTo := f32.Pt(w[0], 0), f32.Pt(w[1], 0)
+		if !approxEqualPt(from, wantFrom, eps) || !approxEqualPt(to, wantTo, eps) {
+			t.Errorf("dash segment %d spans %v..%v, want %v..%v", i, from, to, wantFrom, wantTo)
+		}
+		if from == to {
+			t.Errorf("dash segment %d is closed (From == To); offset would skip capping it", i)
+		}
+	}
+}
+
+// TestDashOddPatternDoubles checks that an odd-length Dashes is repeated
+// once to make it even, per dash's doc comment: pattern [5] must behave
+// like [5, 5] (period 10), so a length-20 straight path dashes to exactly
+// two 5-unit segments, 10 apart.
+func TestDashOddPatternDoubles(t *testing.T) {
+	ps := straightPath(f32.Pt(0, 0), f32.Pt(20, 0))
+	sty := clip.StrokeStyle{Dashes: []float32{5}}
+
+	out := ps.dash(sty)
+	want := [][2]float32{{0, 5}, {10, 15}}
+	if len(out) != len(want) {
+		t.Fatalf("got %d dash segments, want %d", len(out), len(want))
+	}
+	const eps = 1e-3
+	for i, w := range want {
+		from, to := out[i][0].quad.From, out[i][len(out[i])-1].quad.To
+		wantFrom, wantTo := f32.Pt(w[0], 0), f32.Pt(w[1], 0)
+		if !approxEqualPt(from, wantFrom, eps) || !approxEqualPt(to, wantTo, eps) {
+			t.Errorf("dash segment %d spans %v..%v, want %v..%v", i, from, to, wantFrom, wantTo)
+		}
+	}
+}
+
+// TestDashClosedSeamStitch checks that a dash straddling the seam of a
+// closed contour is stitched into a single sub-contour spanning the
+// wrap-around, instead of being cut in two there. On a closed 40-unit
+// square perimeter with pattern [8, 12] and DashPhase 4, the first on-run
+// starts 4 units in (so only 4 of its 8 units land before the seam) and the
+// last on-run starts 4 units before the seam (so the other 4 land after
+// it); stitched together that's a single 8-unit dash from arc-position 36
+// to arc-position 4 (wrapping through the seam at 40/0).
+func TestDashClosedSeamStitch(t *testing.T) {
+	square := straightPath(
+		f32.Pt(0, 0), f32.Pt(10, 0), f32.Pt(10, 10), f32.Pt(0, 10), f32.Pt(0, 0),
+	)
+	sty := clip.StrokeStyle{Dashes: []float32{8, 12}, DashPhase: 4}
+
+	out := square.dash(sty)
+	if len(out) != 2 {
+		t.Fatalf("got %d dash segments, want 2 (the seam-straddling dash stitched into one)", len(out))
+	}
+
+	const eps = 1e-3
+	stitched := out[0]
+	from, to := stitched[0].quad.From, stitched[len(stitched)-1].quad.To
+	wantFrom, wantTo := f32.Pt(0, 4), f32.Pt(4, 0)
+	if !approxEqualPt(from, wantFrom, eps) || !approxEqualPt(to, wantTo, eps) {
+		t.Errorf("stitched seam dash spans %v..%v, want %v..%v", from, to, wantFrom, wantTo)
+	}
+	if from == to {
+		t.Error("stitched seam dash is closed (From == To); offset must treat it as open and cap both ends")
+	}
+
+	other := out[1]
+	from, to = other[0].quad.From, other[len(other)-1].quad.To
+	wantFrom, wantTo = f32.Pt(10, 6), f32.Pt(6, 10)
+	if !approxEqualPt(from, wantFrom, eps) || !approxEqualPt(to, wantTo, eps) {
+		t.Errorf("non-seam dash spans %v..%v, want %v..%v", from, to, wantFrom, wantTo)
+	}
+}
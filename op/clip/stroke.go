@@ -0,0 +1,234 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package clip
+
+import (
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/internal/ops"
+	"gioui.org/op"
+)
+
+// StrokeStyle describes how a stroked path should be capped and joined.
+type StrokeStyle struct {
+	Cap  Capper
+	Join Joiner
+
+	// Dashes is the lengths of alternating "on" and "off" segments to
+	// repeat along the path, measured in the same units as the path
+	// itself. An odd number of entries is repeated once to make it even.
+	// A nil or empty Dashes draws the path unbroken.
+	Dashes []float32
+	// DashPhase offsets the start of the Dashes pattern along the path.
+	DashPhase float32
+}
+
+// Capper caps the start or end of a stroked path.
+type Capper interface {
+	Cap(qs *StrokeQuads, hw float32, pivot, n f32.Point)
+}
+
+// CapperFunc is an adapter to allow the use of ordinary functions as Cappers.
+type CapperFunc func(qs *StrokeQuads, hw float32, pivot, n f32.Point)
+
+// Cap implements Capper.
+func (f CapperFunc) Cap(qs *StrokeQuads, hw float32, pivot, n f32.Point) {
+	f(qs, hw, pivot, n)
+}
+
+// Joiner joins the two segments of a stroked path meeting at pivot.
+type Joiner interface {
+	Join(rhs, lhs *StrokeQuads, hw float32, pivot, n0, n1 f32.Point, r0, r1 float32)
+}
+
+// JoinerFunc is an adapter to allow the use of ordinary functions as Joiners.
+type JoinerFunc func(rhs, lhs *StrokeQuads, hw float32, pivot, n0, n1 f32.Point, r0, r1 float32)
+
+// Join implements Joiner.
+func (f JoinerFunc) Join(rhs, lhs *StrokeQuads, hw float32, pivot, n0, n1 f32.Point, r0, r1 float32) {
+	f(rhs, lhs, hw, pivot, n0, n1, r0, r1)
+}
+
+// StrokeQuads is the quadratic Bézier outline presented to a Capper or
+// Joiner, in the order it was traced.
+type StrokeQuads struct {
+	Segments []StrokeSegment
+}
+
+// StrokeSegment is a single quadratic Bézier segment of a StrokeQuads.
+type StrokeSegment struct {
+	From, Ctrl, To f32.Point
+}
+
+// Pen returns the current end point of qs.
+func (qs *StrokeQuads) Pen() f32.Point {
+	return qs.Segments[len(qs.Segments)-1].To
+}
+
+// LineTo appends a straight line from the current pen position to pt.
+func (qs *StrokeQuads) LineTo(pt f32.Point) {
+	end := qs.Pen()
+	qs.Segments = append(qs.Segments, StrokeSegment{
+		From: end,
+		Ctrl: end.Add(pt).Mul(0.5),
+		To:   pt,
+	})
+}
+
+// Arc appends a circular (or elliptical) arc from the current pen position,
+// in the same terms as Path.Arc.
+func (qs *StrokeQuads) Arc(f1, f2 f32.Point, angle float32) {
+	var (
+		p Path
+		o = new(op.Ops)
+	)
+	p.Begin(o)
+	p.Move(qs.Pen())
+	beg := len(o.Data())
+	p.Arc(f1, f2, angle)
+	end := len(o.Data())
+	raw := o.Data()[beg:end]
+
+	for len(raw) >= (ops.QuadSize + 4) {
+		quad := ops.DecodeQuad(raw[4:])
+		raw = raw[ops.QuadSize+4:]
+		qs.Segments = append(qs.Segments, StrokeSegment{
+			From: quad.From,
+			Ctrl: quad.Ctrl,
+			To:   quad.To,
+		})
+	}
+}
+
+func rot90CCW(p f32.Point) f32.Point { return f32.Pt(-p.Y, +p.X) }
+
+func dotPt(p, q f32.Point) float32 {
+	return p.X*q.X + p.Y*q.Y
+}
+
+func perpDot(p, q f32.Point) float32 {
+	return p.X*q.Y - p.Y*q.X
+}
+
+// Built-in Cappers.
+var (
+	// FlatCap caps the path with a flat line.
+	FlatCap Capper = CapperFunc(flatCap)
+	// SquareCap caps the path with a square.
+	SquareCap Capper = CapperFunc(squareCap)
+	// RoundCap caps the path with a round shape.
+	RoundCap Capper = CapperFunc(roundCap)
+)
+
+func flatCap(qs *StrokeQuads, hw float32, pivot, n0 f32.Point) {
+	end := pivot.Sub(n0)
+	qs.LineTo(end)
+}
+
+func squareCap(qs *StrokeQuads, hw float32, pivot, n0 f32.Point) {
+	var (
+		e       = pivot.Add(rot90CCW(n0))
+		corner1 = e.Add(n0)
+		corner2 = e.Sub(n0)
+		end     = pivot.Sub(n0)
+	)
+
+	qs.LineTo(corner1)
+	qs.LineTo(corner2)
+	qs.LineTo(end)
+}
+
+func roundCap(qs *StrokeQuads, hw float32, pivot, n0 f32.Point) {
+	c := pivot.Sub(qs.Pen())
+	qs.Arc(c, c, math.Pi)
+}
+
+// Built-in Joiners.
+var (
+	// BevelJoin joins path segments with a flat edge.
+	BevelJoin Joiner = JoinerFunc(bevelJoin)
+	// RoundJoin joins path segments with a round shape, on the outer
+	// (convex) side; the inner side is bevelled.
+	RoundJoin Joiner = JoinerFunc(roundJoin)
+)
+
+func bevelJoin(rhs, lhs *StrokeQuads, hw float32, pivot, n0, n1 f32.Point, r0, r1 float32) {
+	rp := pivot.Add(n1)
+	lp := pivot.Sub(n1)
+
+	rhs.LineTo(rp)
+	lhs.LineTo(lp)
+}
+
+func roundJoin(rhs, lhs *StrokeQuads, hw float32, pivot, n0, n1 f32.Point, r0, r1 float32) {
+	if n0 == n1 {
+		return
+	}
+
+	angle := float32(math.Atan2(float64(perpDot(n0, n1)), float64(dotPt(n0, n1))))
+	if perpDot(n0, n1) < 0 {
+		c := pivot.Sub(rhs.Pen())
+		rhs.Arc(c, c, angle)
+		lhs.LineTo(pivot.Sub(n1))
+		return
+	}
+	c := pivot.Sub(lhs.Pen())
+	lhs.Arc(c, c, angle)
+	rhs.LineTo(pivot.Add(n1))
+}
+
+// MiterJoin returns a Joiner that extends the outer edges of adjacent
+// segments until they meet, falling back to BevelJoin when the resulting
+// miter length - measured from pivot, as a multiple of hw - exceeds limit.
+func MiterJoin(limit float32) Joiner {
+	return miterJoin(limit)
+}
+
+type miterJoin float32
+
+func (m miterJoin) Join(rhs, lhs *StrokeQuads, hw float32, pivot, n0, n1 f32.Point, r0, r1 float32) {
+	if n0 == n1 {
+		return
+	}
+
+	var (
+		t0, t1   = rot90CCW(n0), rot90CCW(n1)
+		rp0, rp1 = pivot.Add(n0), pivot.Add(n1)
+		lp0, lp1 = pivot.Sub(n0), pivot.Sub(n1)
+		outer    *StrokeQuads
+		inner    *StrokeQuads
+		op0, op1 f32.Point
+		ip1      f32.Point
+	)
+	if perpDot(n0, n1) < 0 {
+		// The path turns such that rhs is the outer, convex side.
+		outer, op0, op1 = rhs, rp0, rp1
+		inner, ip1 = lhs, lp1
+	} else {
+		outer, op0, op1 = lhs, lp0, lp1
+		inner, ip1 = rhs, rp1
+	}
+
+	if miter, ok := lineIntersect(op0, t0, op1, t1); ok {
+		d := miter.Sub(pivot)
+		if length := float32(math.Hypot(float64(d.X), float64(d.Y))); length <= hw*float32(m) {
+			outer.LineTo(miter)
+			outer.LineTo(op1)
+			inner.LineTo(ip1)
+			return
+		}
+	}
+	bevelJoin(rhs, lhs, hw, pivot, n0, n1, r0, r1)
+}
+
+// lineIntersect returns the intersection of the lines p0+s*d0 and p1+s*d1,
+// and whether the two lines aren't parallel.
+func lineIntersect(p0, d0, p1, d1 f32.Point) (f32.Point, bool) {
+	denom := perpDot(d0, d1)
+	if denom == 0 {
+		return f32.Point{}, false
+	}
+	s := perpDot(p1.Sub(p0), d1) / denom
+	return p0.Add(d0.Mul(s)), true
+}